@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"sync"
+)
+
+// admission implements counting-semaphore style admission control: a
+// global cap on concurrent connections backed by a buffered channel token
+// pool, plus a per-IP cap backed by a mutex-guarded counter map.
+type admission struct {
+	tokens chan struct{}
+
+	maxPerIP int
+	mu       sync.Mutex
+	perIP    map[string]int
+}
+
+func newAdmission(maxConns, maxConnsPerIP int) *admission {
+	a := &admission{
+		maxPerIP: maxConnsPerIP,
+		perIP:    make(map[string]int),
+	}
+	if maxConns > 0 {
+		a.tokens = make(chan struct{}, maxConns)
+		for i := 0; i < maxConns; i++ {
+			a.tokens <- struct{}{}
+		}
+	}
+	return a
+}
+
+// acquire tries to admit conn. On success it returns a release func the
+// caller must call exactly once when the connection ends. On failure it
+// returns the reason the connection was rejected and ok=false.
+func (a *admission) acquire(conn net.Conn) (release func(), reason string, ok bool) {
+	if a.tokens != nil {
+		select {
+		case <-a.tokens:
+		default:
+			return nil, "server busy, try again later", false
+		}
+	}
+
+	ip := hostOf(conn.RemoteAddr())
+	if a.maxPerIP > 0 {
+		a.mu.Lock()
+		if a.perIP[ip] >= a.maxPerIP {
+			a.mu.Unlock()
+			a.releaseToken()
+			return nil, "too many connections from your address, try again later", false
+		}
+		a.perIP[ip]++
+		a.mu.Unlock()
+	}
+
+	released := false
+	return func() {
+		if released {
+			return
+		}
+		released = true
+		if a.maxPerIP > 0 {
+			a.mu.Lock()
+			a.perIP[ip]--
+			if a.perIP[ip] <= 0 {
+				delete(a.perIP, ip)
+			}
+			a.mu.Unlock()
+		}
+		a.releaseToken()
+	}, "", true
+}
+
+func (a *admission) releaseToken() {
+	if a.tokens != nil {
+		a.tokens <- struct{}{}
+	}
+}
+
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return strings.TrimSuffix(addr.String(), ":0")
+	}
+	return host
+}
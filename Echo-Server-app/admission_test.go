@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "tcp" }
+func (a fakeAddr) String() string  { return string(a) }
+
+type fakeConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c fakeConn) RemoteAddr() net.Addr { return c.remote }
+
+func TestAdmissionGlobalCap(t *testing.T) {
+	a := newAdmission(1, 0)
+
+	release1, _, ok := a.acquire(fakeConn{remote: fakeAddr("1.2.3.4:1111")})
+	if !ok {
+		t.Fatalf("expected first connection to be admitted")
+	}
+
+	_, reason, ok := a.acquire(fakeConn{remote: fakeAddr("5.6.7.8:2222")})
+	if ok {
+		t.Fatalf("expected second connection to be rejected once global cap is reached")
+	}
+	if reason == "" {
+		t.Fatalf("expected a rejection reason")
+	}
+
+	release1()
+
+	if _, _, ok := a.acquire(fakeConn{remote: fakeAddr("5.6.7.8:2222")}); !ok {
+		t.Fatalf("expected connection to be admitted after release")
+	}
+}
+
+func TestAdmissionPerIPCap(t *testing.T) {
+	a := newAdmission(0, 1)
+
+	release1, _, ok := a.acquire(fakeConn{remote: fakeAddr("1.2.3.4:1111")})
+	if !ok {
+		t.Fatalf("expected first connection from IP to be admitted")
+	}
+
+	if _, _, ok := a.acquire(fakeConn{remote: fakeAddr("1.2.3.4:2222")}); ok {
+		t.Fatalf("expected second connection from same IP to be rejected")
+	}
+
+	if _, _, ok := a.acquire(fakeConn{remote: fakeAddr("5.6.7.8:3333")}); !ok {
+		t.Fatalf("expected connection from different IP to be admitted")
+	}
+
+	release1()
+	if _, _, ok := a.acquire(fakeConn{remote: fakeAddr("1.2.3.4:4444")}); !ok {
+		t.Fatalf("expected connection to be admitted after release")
+	}
+}
@@ -0,0 +1,151 @@
+// Package clientlog records structured per-connection activity logs. A
+// single background goroutine serializes writes to disk (and, optionally,
+// syslog) so connection goroutines never block on file I/O and never
+// contend over shared file handles the way one log file per connection
+// used to.
+package clientlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// Record is one structured log entry for a single client message or
+// lifecycle event.
+type Record struct {
+	Time      time.Time `json:"ts"`
+	Remote    string    `json:"remote"`
+	Dir       string    `json:"dir"` // "in" or "out"
+	Bytes     int       `json:"bytes"`
+	Msg       string    `json:"msg"`
+	SessionID string    `json:"session_id"`
+}
+
+// Format selects how Records are rendered to the file sink.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// Config configures a Logger's sinks.
+type Config struct {
+	Dir        string // directory for the rotated log file; "" disables file logging
+	Format     Format // FormatText or FormatJSON
+	MaxSizeMB  int    // rotate once the current file exceeds this size
+	MaxAgeDays int    // prune backups older than this many days
+	MaxBackups int    // prune beyond this many backup files
+	Syslog     bool   // also send records to the local syslog daemon
+}
+
+// Logger accepts Records from any number of goroutines and serializes them
+// through one background writer goroutine.
+type Logger struct {
+	records chan Record
+	done    chan struct{}
+	sinks   []io.Closer
+}
+
+// New builds a Logger from cfg and starts its background writer. A zero
+// Config produces a Logger with no sinks, so Log becomes a no-op --
+// logging can be disabled simply by not setting -log-dir.
+func New(cfg Config) (*Logger, error) {
+	l := &Logger{
+		records: make(chan Record, 256),
+		done:    make(chan struct{}),
+	}
+
+	var writers []recordWriter
+
+	if cfg.Dir != "" {
+		rf, err := newRotatingFile(cfg.Dir, "client.log", cfg.MaxSizeMB, cfg.MaxAgeDays, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		l.sinks = append(l.sinks, rf)
+		if cfg.Format == FormatJSON {
+			writers = append(writers, jsonWriter{w: rf})
+		} else {
+			writers = append(writers, textWriter{w: rf})
+		}
+	}
+
+	if cfg.Syslog {
+		sw, err := newSyslogWriter()
+		if err != nil {
+			return nil, fmt.Errorf("clientlog: opening syslog: %w", err)
+		}
+		l.sinks = append(l.sinks, sw)
+		writers = append(writers, sw)
+	}
+
+	go l.run(writers)
+	return l, nil
+}
+
+// recordWriter renders and writes one Record to a sink.
+type recordWriter interface {
+	writeRecord(Record) error
+}
+
+func (l *Logger) run(writers []recordWriter) {
+	defer close(l.done)
+	for rec := range l.records {
+		for _, w := range writers {
+			if err := w.writeRecord(rec); err != nil {
+				log.Printf("clientlog: write failed: %v", err)
+			}
+		}
+	}
+}
+
+// Log enqueues rec for the background writer. It never blocks the caller
+// on disk I/O; if the queue is full the record is dropped rather than
+// stalling the connection goroutine that produced it.
+func (l *Logger) Log(rec Record) {
+	if l == nil {
+		return
+	}
+	select {
+	case l.records <- rec:
+	default:
+		log.Printf("clientlog: dropping record, queue full")
+	}
+}
+
+// Close stops accepting new records, waits for the queue to drain, and
+// closes every sink.
+func (l *Logger) Close() {
+	if l == nil {
+		return
+	}
+	close(l.records)
+	<-l.done
+	for _, s := range l.sinks {
+		s.Close()
+	}
+}
+
+type textWriter struct{ w io.Writer }
+
+func (t textWriter) writeRecord(rec Record) error {
+	_, err := fmt.Fprintf(t.w, "[%s] %s %s %s (%d bytes) session=%s\n",
+		rec.Time.Format(time.RFC3339), rec.Remote, rec.Dir, rec.Msg, rec.Bytes, rec.SessionID)
+	return err
+}
+
+type jsonWriter struct{ w io.Writer }
+
+func (j jsonWriter) writeRecord(rec Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = j.w.Write(b)
+	return err
+}
@@ -0,0 +1,65 @@
+package clientlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerWritesJSONLines(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(Config{Dir: dir, Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	l.Log(Record{Time: time.Now(), Remote: "1.2.3.4:5555", Dir: "in", Bytes: 5, Msg: "hello", SessionID: "abc"})
+	l.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "client.log"))
+	if err != nil {
+		t.Fatalf("reading log file: %v", err)
+	}
+	if !strings.Contains(string(data), `"session_id":"abc"`) {
+		t.Fatalf("expected session_id field in %q", data)
+	}
+	if !strings.Contains(string(data), `"msg":"hello"`) {
+		t.Fatalf("expected msg field in %q", data)
+	}
+}
+
+func TestLoggerRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	// maxSizeMB can't express a few-byte threshold, so drive rotation
+	// through the rotatingFile directly at a byte granularity.
+	rf, err := newRotatingFile(dir, "client.log", 0, 0, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	rf.maxSize = 10
+
+	for i := 0; i < 5; i++ {
+		if _, err := rf.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	rf.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("expected rotation to produce backup files, got %d entries", len(entries))
+	}
+}
+
+func TestNewSessionIDIsUnique(t *testing.T) {
+	a := NewSessionID()
+	b := NewSessionID()
+	if a == b {
+		t.Fatalf("expected distinct session ids, got %q twice", a)
+	}
+}
@@ -0,0 +1,138 @@
+package clientlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatingFile is a small in-tree equivalent of the lumberjack rolling
+// file writer: it writes to a single current file, rotating to a
+// timestamped backup once it exceeds maxSizeBytes, and prunes backups
+// beyond maxBackups or older than maxAge.
+type rotatingFile struct {
+	mu sync.Mutex
+
+	dir      string
+	name     string
+	maxSize  int64
+	maxAge   time.Duration
+	maxCount int
+
+	file *os.File
+	size int64
+}
+
+func newRotatingFile(dir, name string, maxSizeMB, maxAgeDays, maxBackups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("clientlog: creating log dir: %w", err)
+	}
+	rf := &rotatingFile{
+		dir:      dir,
+		name:     name,
+		maxSize:  int64(maxSizeMB) * 1024 * 1024,
+		maxAge:   time.Duration(maxAgeDays) * 24 * time.Hour,
+		maxCount: maxBackups,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) path() string {
+	return filepath.Join(rf.dir, rf.name)
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("clientlog: opening log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("clientlog: statting log file: %w", err)
+	}
+	rf.file = f
+	rf.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer. Only ever called from the logger's single
+// background goroutine, so it needs no locking for that reason, but the
+// mutex also guards against concurrent Close.
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.maxSize > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	backup := filepath.Join(rf.dir, fmt.Sprintf("%s.%s", rf.name, time.Now().Format("20060102T150405.000000000")))
+	if err := os.Rename(rf.path(), backup); err != nil {
+		return fmt.Errorf("clientlog: rotating log file: %w", err)
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.prune()
+	return nil
+}
+
+// prune removes backups beyond maxCount or older than maxAge. Best-effort:
+// errors are ignored since a failed cleanup shouldn't stop logging.
+func (rf *rotatingFile) prune() {
+	entries, err := os.ReadDir(rf.dir)
+	if err != nil {
+		return
+	}
+	prefix := rf.name + "."
+	var backups []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), prefix) {
+			backups = append(backups, e.Name())
+		}
+	}
+	sort.Strings(backups) // timestamp suffix sorts chronologically
+
+	now := time.Now()
+	cutoff := -1
+	if rf.maxCount > 0 && len(backups) > rf.maxCount {
+		cutoff = len(backups) - rf.maxCount
+	}
+	for i, name := range backups {
+		remove := i < cutoff
+		if rf.maxAge > 0 {
+			if info, err := os.Stat(filepath.Join(rf.dir, name)); err == nil {
+				if now.Sub(info.ModTime()) > rf.maxAge {
+					remove = true
+				}
+			}
+		}
+		if remove {
+			os.Remove(filepath.Join(rf.dir, name))
+		}
+	}
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.file.Close()
+}
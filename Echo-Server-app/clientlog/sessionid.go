@@ -0,0 +1,21 @@
+package clientlog
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// NewSessionID returns a random RFC 4122 version-4 UUID string, used to
+// correlate every log record for one connection.
+func NewSessionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand failing is effectively unrecoverable for anything
+		// else in the process too, but a session id is non-critical, so
+		// fall back instead of taking the server down.
+		return "00000000-0000-4000-8000-000000000000"
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package clientlog
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// syslogWriter sends records to the local syslog daemon, available on
+// unix-like platforms only.
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+func newSyslogWriter() (*syslogWriter, error) {
+	w, err := syslog.New(syslog.LOG_INFO, "echo-server")
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{w: w}, nil
+}
+
+func (s *syslogWriter) writeRecord(rec Record) error {
+	return s.w.Info(fmt.Sprintf("%s %s %s (%d bytes) session=%s", rec.Remote, rec.Dir, rec.Msg, rec.Bytes, rec.SessionID))
+}
+
+func (s *syslogWriter) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package clientlog
+
+import "errors"
+
+// syslogWriter is unavailable on Windows; -log-syslog fails fast there
+// instead of silently doing nothing.
+type syslogWriter struct{}
+
+func newSyslogWriter() (*syslogWriter, error) {
+	return nil, errors.New("clientlog: syslog is not supported on windows")
+}
+
+func (s *syslogWriter) writeRecord(Record) error { return nil }
+func (s *syslogWriter) Close() error             { return nil }
@@ -0,0 +1,173 @@
+// Package codec abstracts the wire framing used to read and write discrete
+// messages over a connection, so the connection handler doesn't need to
+// know whether it's talking newline-delimited text, length-prefixed
+// frames, or JSON-RPC. It replaces the ad-hoc 1024-byte length check that
+// used to live inline in the handler.
+package codec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultMaxFrameSize is used when a non-positive max frame size is given.
+const DefaultMaxFrameSize = 1024
+
+// ErrFrameTooLarge is returned by ReadMessage when a frame exceeds the
+// codec's configured maximum size.
+var ErrFrameTooLarge = errors.New("codec: frame exceeds maximum size")
+
+// Codec reads and writes discrete application messages over a connection,
+// handling whatever framing its protocol requires.
+type Codec interface {
+	// ReadMessage reads and returns the next complete message with framing
+	// removed. It returns the underlying error (e.g. io.EOF) unmodified so
+	// callers can tell a clean disconnect from a protocol violation.
+	ReadMessage() (string, error)
+
+	// WriteMessage frames and writes msg.
+	WriteMessage(msg string) error
+
+	// WriteError frames and writes a protocol-level error notice.
+	WriteError(err error) error
+}
+
+// New constructs the Codec named by proto ("line", "length" or "jsonrpc";
+// "" is an alias for "line"). maxFrame bounds the size of a single message;
+// non-positive values fall back to DefaultMaxFrameSize.
+func New(proto string, rw io.ReadWriter, maxFrame int) (Codec, error) {
+	if maxFrame <= 0 {
+		maxFrame = DefaultMaxFrameSize
+	}
+	switch proto {
+	case "", "line":
+		return newLineCodec(rw, maxFrame), nil
+	case "length":
+		return newLengthCodec(rw, maxFrame), nil
+	case "jsonrpc":
+		return newJSONRPCCodec(rw, maxFrame), nil
+	default:
+		return nil, fmt.Errorf("codec: unknown protocol %q", proto)
+	}
+}
+
+// lineCodec implements the server's original newline-delimited text
+// framing.
+type lineCodec struct {
+	r        *bufio.Reader
+	w        *bufio.Writer
+	maxFrame int
+}
+
+func newLineCodec(rw io.ReadWriter, maxFrame int) *lineCodec {
+	return &lineCodec{r: bufio.NewReader(rw), w: bufio.NewWriter(rw), maxFrame: maxFrame}
+}
+
+func (c *lineCodec) ReadMessage() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) > c.maxFrame {
+		return "", ErrFrameTooLarge
+	}
+	return line, nil
+}
+
+func (c *lineCodec) WriteMessage(msg string) error {
+	if _, err := c.w.WriteString(msg + "\n"); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+func (c *lineCodec) WriteError(err error) error {
+	return c.WriteMessage("Error: " + err.Error())
+}
+
+// lengthCodec frames each message with a 4-byte big-endian length prefix.
+type lengthCodec struct {
+	r        *bufio.Reader
+	w        *bufio.Writer
+	maxFrame uint32
+}
+
+func newLengthCodec(rw io.ReadWriter, maxFrame int) *lengthCodec {
+	return &lengthCodec{r: bufio.NewReader(rw), w: bufio.NewWriter(rw), maxFrame: uint32(maxFrame)}
+}
+
+func (c *lengthCodec) ReadMessage() (string, error) {
+	var sizeBuf [4]byte
+	if _, err := io.ReadFull(c.r, sizeBuf[:]); err != nil {
+		return "", err
+	}
+	size := binary.BigEndian.Uint32(sizeBuf[:])
+	if size > c.maxFrame {
+		return "", ErrFrameTooLarge
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func (c *lengthCodec) WriteMessage(msg string) error {
+	var sizeBuf [4]byte
+	binary.BigEndian.PutUint32(sizeBuf[:], uint32(len(msg)))
+	if _, err := c.w.Write(sizeBuf[:]); err != nil {
+		return err
+	}
+	if _, err := c.w.WriteString(msg); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+func (c *lengthCodec) WriteError(err error) error {
+	return c.WriteMessage("Error: " + err.Error())
+}
+
+// jsonrpcCodec frames each JSON-RPC message as a single newline-delimited
+// line of JSON text; decoding the envelope itself is the jsonrpc package's
+// job, not this codec's.
+type jsonrpcCodec struct {
+	r        *bufio.Reader
+	w        *bufio.Writer
+	maxFrame int
+}
+
+func newJSONRPCCodec(rw io.ReadWriter, maxFrame int) *jsonrpcCodec {
+	return &jsonrpcCodec{r: bufio.NewReader(rw), w: bufio.NewWriter(rw), maxFrame: maxFrame}
+}
+
+func (c *jsonrpcCodec) ReadMessage() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) > c.maxFrame {
+		return "", ErrFrameTooLarge
+	}
+	return line, nil
+}
+
+func (c *jsonrpcCodec) WriteMessage(msg string) error {
+	if _, err := c.w.WriteString(msg + "\n"); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// WriteError reports a framing-level failure (e.g. an oversized frame) as
+// a JSON-RPC parse error envelope, since there is no request id to
+// correlate it with.
+func (c *jsonrpcCodec) WriteError(err error) error {
+	return c.WriteMessage(fmt.Sprintf(`{"jsonrpc":"2.0","error":{"code":-32700,"message":%q},"id":null}`, err.Error()))
+}
@@ -0,0 +1,68 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLineCodecRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	c := newLineCodec(&buf, 64)
+
+	if err := c.WriteMessage("hello"); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	got, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if got != "hello" {
+		t.Fatalf("got %q, want %q", got, "hello")
+	}
+}
+
+func TestLineCodecFrameTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("this line is way too long\n")
+	c := newLineCodec(&buf, 4)
+
+	if _, err := c.ReadMessage(); err != ErrFrameTooLarge {
+		t.Fatalf("got err %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestLengthCodecRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	c := newLengthCodec(&buf, 64)
+
+	if err := c.WriteMessage("hello"); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	got, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if got != "hello" {
+		t.Fatalf("got %q, want %q", got, "hello")
+	}
+}
+
+func TestLengthCodecFrameTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	full := newLengthCodec(&buf, 64)
+	if err := full.WriteMessage("0123456789"); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	small := newLengthCodec(&buf, 4)
+	if _, err := small.ReadMessage(); err != ErrFrameTooLarge {
+		t.Fatalf("got err %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestNewUnknownProto(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := New("carrier-pigeon", &buf, 0); err == nil {
+		t.Fatalf("expected error for unknown protocol")
+	}
+}
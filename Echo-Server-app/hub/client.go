@@ -0,0 +1,256 @@
+package hub
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WenYanelly16/TCP-Echo-Server-app/clientlog"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/codec"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/ratelimit"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/stats"
+)
+
+var anonCounter struct {
+	sync.Mutex
+	n int
+}
+
+func nextAnonNick() string {
+	anonCounter.Lock()
+	defer anonCounter.Unlock()
+	anonCounter.n++
+	return "guest" + strconv.Itoa(anonCounter.n)
+}
+
+// Client represents one connected socket. Its nick and room are only ever
+// mutated from the hub's run loop; strikes and quitTimer belong to the
+// client's own read goroutine. send is written to only from the hub's run loop (readLoop
+// routes local replies there via reply/h.cmds) and is read only by
+// writeLoop, so its close in closeOutbox never races a concurrent send.
+type Client struct {
+	hub   *Hub
+	conn  net.Conn
+	codec codec.Codec
+
+	send chan string
+
+	sessionID string
+	nick      string
+	room      string
+
+	limiter *ratelimit.Bucket
+	strikes int // consecutive inbound messages rejected by limiter
+
+	idleTimer *time.Timer // reset on every successful read; closes conn on fire
+	quitTimer *time.Timer // started on /quit or bye to guarantee the socket closes
+}
+
+// rateLimitStrikes is how many consecutive rate-limit violations a client
+// is allowed before it is disconnected for flooding.
+const rateLimitStrikes = 5
+
+// quitGrace bounds how long a client's final "goodbye" message is given to
+// flush before the connection is closed out from under it.
+const quitGrace = 5 * time.Second
+
+// Serve registers conn with the hub, then blocks until the client
+// disconnects or the hub shuts it down. c frames the wire protocol (line
+// or length, per the server's -proto flag); it is the hub package's
+// replacement for the old standalone handleConnection. If presetNick is
+// non-empty (e.g. a verified TLS client certificate's CN), it is used
+// instead of an auto-generated guest nick.
+func (h *Hub) Serve(conn net.Conn, c codec.Codec, presetNick string) {
+	stats.ConnectionOpened()
+	defer stats.ConnectionClosed()
+
+	nick := presetNick
+	if nick == "" {
+		nick = nextAnonNick()
+	}
+
+	cl := &Client{
+		hub:       h,
+		conn:      conn,
+		codec:     c,
+		send:      make(chan string, outboxSize),
+		nick:      nick,
+		sessionID: clientlog.NewSessionID(),
+	}
+	if h.msgRate > 0 {
+		cl.limiter = ratelimit.New(h.msgRate, h.msgBurst)
+	}
+	if h.idleTimeout > 0 {
+		cl.idleTimer = time.AfterFunc(h.idleTimeout, func() { conn.Close() })
+		defer cl.idleTimer.Stop()
+	}
+
+	h.cmds <- command{kind: cmdRegister, client: cl}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cl.writeLoop()
+	}()
+
+	cl.readLoop()
+	h.cmds <- command{kind: cmdUnregister, client: cl}
+	// Wait for writeLoop to drain any final queued message (e.g. a rate
+	// limit or shutdown notice) before closing the socket out from under
+	// it; closing first would race the notice off the wire.
+	wg.Wait()
+	conn.Close()
+	if cl.quitTimer != nil {
+		cl.quitTimer.Stop()
+	}
+}
+
+// closeOutbox closes send, which unblocks writeLoop. Only called from the
+// hub's run loop, so it is safe even when deliver and onUnregister race
+// against a client disconnecting on its own.
+func (c *Client) closeOutbox() {
+	defer func() { recover() }()
+	close(c.send)
+}
+
+func (c *Client) writeLoop() {
+	// Once the outbox is drained and closed (or a write fails), close the
+	// connection so a read blocked in readLoop returns too; this is what
+	// brings clients down after Hub.Shutdown.
+	defer c.conn.Close()
+	for msg := range c.send {
+		c.hub.logger.Log(clientlog.Record{
+			Time:      time.Now(),
+			Remote:    c.conn.RemoteAddr().String(),
+			Dir:       "out",
+			Bytes:     len(msg),
+			Msg:       msg,
+			SessionID: c.sessionID,
+		})
+		if err := c.codec.WriteMessage(msg); err != nil {
+			return
+		}
+	}
+}
+
+func (c *Client) readLoop() {
+	for {
+		line, err := c.codec.ReadMessage()
+		if err != nil {
+			if err == codec.ErrFrameTooLarge {
+				c.codec.WriteError(err)
+				continue
+			}
+			return
+		}
+		if c.idleTimer != nil {
+			c.idleTimer.Reset(c.hub.idleTimeout)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		stats.MessageHandled()
+		c.hub.logger.Log(clientlog.Record{
+			Time:      time.Now(),
+			Remote:    c.conn.RemoteAddr().String(),
+			Dir:       "in",
+			Bytes:     len(line),
+			Msg:       line,
+			SessionID: c.sessionID,
+		})
+
+		if c.limiter != nil && !c.limiter.Allow() {
+			c.strikes++
+			if c.strikes >= rateLimitStrikes {
+				c.reply("* disconnecting: sustained rate limit violation")
+				return
+			}
+			c.reply("* slow down, you're sending messages too fast")
+			continue
+		}
+		c.strikes = 0
+
+		if strings.EqualFold(line, "bye") {
+			c.reply("Goodbye!")
+			c.beginQuit()
+			return
+		}
+
+		if strings.HasPrefix(line, "/") {
+			if !c.handleCommand(line) {
+				return
+			}
+			continue
+		}
+
+		c.hub.cmds <- command{kind: cmdBroadcast, client: c, arg1: line}
+	}
+}
+
+// beginQuit starts quitTimer, which force-closes the connection after
+// quitGrace even if the final message's Flush is stuck, guaranteeing the
+// socket comes down.
+func (c *Client) beginQuit() {
+	c.quitTimer = time.AfterFunc(quitGrace, func() { c.conn.Close() })
+}
+
+// reply routes a message meant only for c through the hub's run loop
+// instead of writing c.send directly. c.send is only ever closed from the
+// hub goroutine (via closeOutbox); a direct send from here could race that
+// close and panic, so every local reply goes through h.cmds like every
+// other mutation.
+func (c *Client) reply(msg string) {
+	c.hub.cmds <- command{kind: cmdReply, client: c, arg1: msg}
+}
+
+// handleCommand dispatches a "/"-prefixed line. It returns false when the
+// connection should be torn down (e.g. /quit).
+func (c *Client) handleCommand(line string) bool {
+	parts := strings.SplitN(line, " ", 2)
+	name := parts[0]
+	var rest string
+	if len(parts) > 1 {
+		rest = strings.TrimSpace(parts[1])
+	}
+
+	switch name {
+	case "/nick":
+		c.hub.cmds <- command{kind: cmdNick, client: c, arg1: rest}
+	case "/join":
+		c.hub.cmds <- command{kind: cmdJoin, client: c, arg1: rest}
+	case "/leave":
+		c.hub.cmds <- command{kind: cmdLeave, client: c}
+	case "/rooms":
+		c.hub.cmds <- command{kind: cmdRooms, client: c}
+	case "/who":
+		c.hub.cmds <- command{kind: cmdWho, client: c}
+	case "/msg":
+		to, text, ok := strings.Cut(rest, " ")
+		if !ok {
+			c.reply("* usage: /msg <user> <text>")
+			break
+		}
+		c.hub.cmds <- command{kind: cmdMsg, client: c, arg1: to, arg2: text}
+	case "/time":
+		c.reply(time.Now().Format(time.RFC3339))
+	case "/echo":
+		if rest == "" {
+			c.reply("Usage: /echo <message>")
+			break
+		}
+		c.reply(rest)
+	case "/quit":
+		c.reply("Closing connection")
+		c.beginQuit()
+		return false
+	default:
+		c.reply(fmt.Sprintf("* unknown command %q", name))
+	}
+	return true
+}
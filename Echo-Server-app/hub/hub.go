@@ -0,0 +1,300 @@
+// Package hub implements the chat fan-out core of the echo server: a single
+// goroutine owns all shared state (rooms, nicknames, membership) and every
+// other goroutine talks to it over channels rather than locks, following the
+// "share memory by communicating" pattern.
+package hub
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/WenYanelly16/TCP-Echo-Server-app/clientlog"
+)
+
+const (
+	defaultRoom = "lobby"
+
+	// outboxSize bounds each client's outbound channel. A client that can't
+	// keep up with its outbox gets dropped instead of stalling the hub.
+	outboxSize = 32
+)
+
+type cmdKind int
+
+const (
+	cmdRegister cmdKind = iota
+	cmdUnregister
+	cmdNick
+	cmdJoin
+	cmdLeave
+	cmdRooms
+	cmdWho
+	cmdMsg
+	cmdBroadcast
+	cmdReply
+)
+
+// command is sent by a Client's read loop to the hub's run loop.
+type command struct {
+	kind   cmdKind
+	client *Client
+	arg1   string
+	arg2   string
+}
+
+// Hub owns room membership and message fan-out for every connected Client.
+// All fields below are only ever touched from run, so Hub itself needs no
+// locking.
+type Hub struct {
+	cmds     chan command
+	shutdown chan string
+	done     chan struct{}
+
+	clients map[*Client]bool
+	rooms   map[string]map[*Client]bool
+	nicks   map[string]*Client
+
+	msgRate  float64
+	msgBurst int
+
+	idleTimeout time.Duration
+
+	logger *clientlog.Logger
+}
+
+// Options configures behavior applied to every Client the Hub serves. A
+// zero MsgRate or IdleTimeout, or a nil Logger, disables that feature;
+// MsgBurst is only used when MsgRate is set, and a zero MsgBurst there lets
+// no message through.
+type Options struct {
+	MsgRate  float64 // inbound messages/sec allowed per connection
+	MsgBurst int     // burst capacity for MsgRate
+
+	IdleTimeout time.Duration // disconnect a client after this long without a message
+
+	Logger *clientlog.Logger // nil disables per-message activity logging
+}
+
+// New creates a Hub and starts its run loop. Clients are registered and
+// unregistered only through Serve; Serve and Shutdown are Hub's only public
+// mutation points.
+func New(opts Options) *Hub {
+	h := &Hub{
+		cmds:        make(chan command, 64),
+		shutdown:    make(chan string),
+		done:        make(chan struct{}),
+		clients:     make(map[*Client]bool),
+		rooms:       make(map[string]map[*Client]bool),
+		nicks:       make(map[string]*Client),
+		msgRate:     opts.MsgRate,
+		msgBurst:    opts.MsgBurst,
+		idleTimeout: opts.IdleTimeout,
+		logger:      opts.Logger,
+	}
+	go h.run()
+	return h
+}
+
+// Shutdown broadcasts notice to every connected client, closes their
+// connections, and stops the hub's run loop. It blocks until the run loop
+// has exited.
+func (h *Hub) Shutdown(notice string) {
+	h.shutdown <- notice
+	<-h.done
+}
+
+func (h *Hub) run() {
+	defer close(h.done)
+	for {
+		select {
+		case cmd := <-h.cmds:
+			h.handle(cmd)
+		case notice := <-h.shutdown:
+			for c := range h.clients {
+				h.deliver(c, "* "+notice)
+				c.closeOutbox()
+			}
+			return
+		}
+	}
+}
+
+func (h *Hub) handle(cmd command) {
+	switch cmd.kind {
+	case cmdRegister:
+		h.onRegister(cmd.client)
+	case cmdUnregister:
+		h.onUnregister(cmd.client)
+	case cmdNick:
+		h.onNick(cmd.client, cmd.arg1)
+	case cmdJoin:
+		h.onJoin(cmd.client, cmd.arg1)
+	case cmdLeave:
+		h.onLeave(cmd.client)
+	case cmdRooms:
+		h.onRooms(cmd.client)
+	case cmdWho:
+		h.onWho(cmd.client)
+	case cmdMsg:
+		h.onMsg(cmd.client, cmd.arg1, cmd.arg2)
+	case cmdBroadcast:
+		h.onBroadcast(cmd.client, cmd.arg1)
+	case cmdReply:
+		h.deliver(cmd.client, cmd.arg1)
+	}
+}
+
+func (h *Hub) onRegister(c *Client) {
+	h.clients[c] = true
+	h.nicks[c.nick] = c
+	h.joinRoom(c, defaultRoom)
+	h.deliver(c, fmt.Sprintf("* welcome, you are %s in #%s", c.nick, defaultRoom))
+}
+
+func (h *Hub) onUnregister(c *Client) {
+	if !h.clients[c] {
+		return
+	}
+	h.leaveRoom(c)
+	delete(h.clients, c)
+	if h.nicks[c.nick] == c {
+		delete(h.nicks, c.nick)
+	}
+	c.closeOutbox()
+}
+
+func (h *Hub) onNick(c *Client, nick string) {
+	if nick == "" {
+		h.deliver(c, "* usage: /nick <name>")
+		return
+	}
+	if _, taken := h.nicks[nick]; taken {
+		h.deliver(c, fmt.Sprintf("* nick %q is already in use", nick))
+		return
+	}
+	old := c.nick
+	delete(h.nicks, old)
+	c.nick = nick
+	h.nicks[nick] = c
+	h.deliver(c, fmt.Sprintf("* you are now known as %s", nick))
+	h.broadcastRoom(c.room, fmt.Sprintf("* %s is now known as %s", old, nick), nil)
+}
+
+func (h *Hub) onJoin(c *Client, room string) {
+	if room == "" {
+		h.deliver(c, "* usage: /join <room>")
+		return
+	}
+	if room == c.room {
+		h.deliver(c, fmt.Sprintf("* already in #%s", room))
+		return
+	}
+	h.leaveRoom(c)
+	h.joinRoom(c, room)
+}
+
+func (h *Hub) onLeave(c *Client) {
+	h.leaveRoom(c)
+	h.joinRoom(c, defaultRoom)
+}
+
+func (h *Hub) joinRoom(c *Client, room string) {
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*Client]bool)
+	}
+	h.rooms[room][c] = true
+	c.room = room
+	h.deliver(c, fmt.Sprintf("* joined #%s", room))
+	h.broadcastRoom(room, fmt.Sprintf("* %s joined #%s", c.nick, room), c)
+}
+
+func (h *Hub) leaveRoom(c *Client) {
+	room := c.room
+	if room == "" {
+		return
+	}
+	delete(h.rooms[room], c)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+	c.room = ""
+	h.broadcastRoom(room, fmt.Sprintf("* %s left #%s", c.nick, room), c)
+}
+
+func (h *Hub) onRooms(c *Client) {
+	if len(h.rooms) == 0 {
+		h.deliver(c, "* no rooms")
+		return
+	}
+	for room, members := range h.rooms {
+		h.deliver(c, fmt.Sprintf("* #%s (%d)", room, len(members)))
+	}
+}
+
+func (h *Hub) onWho(c *Client) {
+	members := h.rooms[c.room]
+	if len(members) == 0 {
+		h.deliver(c, fmt.Sprintf("* #%s is empty", c.room))
+		return
+	}
+	for m := range members {
+		h.deliver(c, fmt.Sprintf("* %s", m.nick))
+	}
+}
+
+func (h *Hub) onMsg(c *Client, to, text string) {
+	target, ok := h.nicks[to]
+	if !ok {
+		h.deliver(c, fmt.Sprintf("* no such user %q", to))
+		return
+	}
+	h.deliver(target, fmt.Sprintf("[pm from %s] %s", c.nick, text))
+	h.deliver(c, fmt.Sprintf("[pm to %s] %s", to, text))
+}
+
+func (h *Hub) onBroadcast(c *Client, text string) {
+	h.broadcastRoom(c.room, fmt.Sprintf("[#%s] %s: %s", c.room, c.nick, text), nil)
+}
+
+// broadcastRoom delivers msg to every member of room except skip (if not
+// nil).
+func (h *Hub) broadcastRoom(room, msg string, skip *Client) {
+	for m := range h.rooms[room] {
+		if m == skip {
+			continue
+		}
+		h.deliver(m, msg)
+	}
+}
+
+// deliver sends msg to c's outbox. If the outbox is full the client is too
+// slow to keep up and is dropped rather than letting it stall the hub: a
+// queued message is evicted to make room for a final "too slow" notice, and
+// the connection is closed outright so a reader blocked waiting on input
+// (the common case for a stalled consumer) is unblocked too.
+//
+// c may already have been dropped by the time a queued command naming it
+// (e.g. a client's own /time reply) reaches the front of h.cmds, since a
+// client's goroutine and the hub's run loop race to enqueue commands; skip
+// silently rather than sending on the outbox closeOutbox already closed.
+func (h *Hub) deliver(c *Client, msg string) {
+	if !h.clients[c] {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+		log.Printf("hub: dropping slow client %s (%s)", c.nick, c.conn.RemoteAddr())
+		select {
+		case <-c.send:
+		default:
+		}
+		select {
+		case c.send <- "* disconnecting: too slow":
+		default:
+		}
+		c.conn.Close()
+		h.onUnregister(c)
+	}
+}
@@ -0,0 +1,238 @@
+package hub
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/WenYanelly16/TCP-Echo-Server-app/codec"
+)
+
+// pipeClient wires a Hub up to an in-memory net.Pipe connection so tests
+// don't need a real listener.
+type pipeClient struct {
+	reader *bufio.Reader
+	conn   net.Conn
+}
+
+func connectClient(t *testing.T, h *Hub) *pipeClient {
+	t.Helper()
+	serverSide, clientSide := net.Pipe()
+	serverCodec, err := codec.New("line", serverSide, 0)
+	if err != nil {
+		t.Fatalf("codec.New: %v", err)
+	}
+	go h.Serve(serverSide, serverCodec, "")
+	return &pipeClient{reader: bufio.NewReader(clientSide), conn: clientSide}
+}
+
+func (p *pipeClient) send(t *testing.T, line string) {
+	t.Helper()
+	if _, err := p.conn.Write([]byte(line + "\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func (p *pipeClient) expect(t *testing.T, contains string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		p.conn.SetReadDeadline(deadline)
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("waiting for %q: %v", contains, err)
+		}
+		if contains == "" || (len(line) > 0 && stringsContains(line, contains)) {
+			return
+		}
+	}
+}
+
+func stringsContains(haystack, needle string) bool {
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if haystack[i:i+len(needle)] == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func TestJoinAndBroadcast(t *testing.T) {
+	h := New(Options{})
+	defer h.Shutdown("test done")
+
+	a := connectClient(t, h)
+	a.expect(t, "welcome")
+
+	b := connectClient(t, h)
+	b.expect(t, "welcome")
+
+	a.send(t, "hello room")
+	b.expect(t, "hello room")
+}
+
+func TestNickCollision(t *testing.T) {
+	h := New(Options{})
+	defer h.Shutdown("test done")
+
+	a := connectClient(t, h)
+	a.expect(t, "welcome")
+	a.send(t, "/nick alice")
+	a.expect(t, "now known as alice")
+
+	b := connectClient(t, h)
+	b.expect(t, "welcome")
+	b.send(t, "/nick alice")
+	b.expect(t, "already in use")
+}
+
+func TestSlowClientIsDropped(t *testing.T) {
+	h := New(Options{})
+	defer h.Shutdown("test done")
+
+	slow := connectClient(t, h)
+	slow.expect(t, "welcome")
+
+	fast := connectClient(t, h)
+	fast.expect(t, "welcome")
+
+	// Broadcasts echo back to the sender too, so fast must drain its own
+	// inbox concurrently or it would back up right along with slow.
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			if _, err := fast.conn.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Flood far past the outbox capacity without ever reading from slow's
+	// side; the hub must drop it rather than stall.
+	for i := 0; i < outboxSize*4; i++ {
+		fast.send(t, "flood message")
+	}
+
+	slow.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1)
+	_, err := slow.conn.Read(buf)
+	if err == nil {
+		t.Fatalf("expected slow client's connection to eventually be closed or stalled, not actively readable forever")
+	}
+}
+
+func TestFloodingClientIsRateLimited(t *testing.T) {
+	h := New(Options{MsgRate: 2, MsgBurst: 2})
+	defer h.Shutdown("test done")
+
+	c := connectClient(t, h)
+	c.expect(t, "welcome")
+
+	// Flood on a separate goroutine and read concurrently: net.Pipe is
+	// synchronous, so once the server has seen enough strikes it queues its
+	// disconnect notice and stops reading; blasting writes serially on
+	// this goroutine would then block forever on a write nobody reads.
+	go func() {
+		for i := 0; i < rateLimitStrikes+5; i++ {
+			if _, err := c.conn.Write([]byte("spam\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	c.expect(t, "disconnecting")
+}
+
+func TestIdleClientIsDisconnected(t *testing.T) {
+	const idleTimeout = 100 * time.Millisecond
+	const epsilon = 150 * time.Millisecond
+
+	h := New(Options{IdleTimeout: idleTimeout})
+	defer h.Shutdown("test done")
+
+	c := connectClient(t, h)
+	c.expect(t, "welcome")
+
+	start := time.Now()
+	c.conn.SetReadDeadline(start.Add(idleTimeout + epsilon))
+	buf := make([]byte, 1)
+	_, err := c.conn.Read(buf)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatalf("expected the idle client's connection to be closed")
+	}
+	if isDeadlineExceeded(err) {
+		t.Fatalf("idle client was not disconnected within timeout+epsilon (waited %s)", elapsed)
+	}
+}
+
+func TestActiveClientIsNotDisconnected(t *testing.T) {
+	const idleTimeout = 100 * time.Millisecond
+
+	h := New(Options{IdleTimeout: idleTimeout})
+	defer h.Shutdown("test done")
+
+	c := connectClient(t, h)
+	c.expect(t, "welcome")
+
+	deadline := time.Now().Add(idleTimeout * 5)
+	for time.Now().Before(deadline) {
+		c.send(t, "still here")
+		c.expect(t, "still here")
+		time.Sleep(idleTimeout / 2)
+	}
+}
+
+// TestReplyDoesNotRaceDrop reproduces a client that gets dropped for
+// flooding (its outbox closed by the hub) at the same moment its own
+// goroutine is issuing a local reply command (e.g. /time): the reply must
+// be silently discarded by deliver, not sent on the now-closed outbox.
+func TestReplyDoesNotRaceDrop(t *testing.T) {
+	h := New(Options{})
+	defer h.Shutdown("test done")
+
+	flooder := connectClient(t, h)
+	flooder.expect(t, "welcome")
+
+	other := connectClient(t, h)
+	other.expect(t, "welcome")
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			if _, err := other.conn.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	stop := time.After(200 * time.Millisecond)
+	floodDone := make(chan struct{})
+	go func() {
+		defer close(floodDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err := flooder.conn.Write([]byte("/time\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 2000; i++ {
+		if _, err := other.conn.Write([]byte("/time\n")); err != nil {
+			break
+		}
+	}
+	<-floodDone
+}
+
+func isDeadlineExceeded(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
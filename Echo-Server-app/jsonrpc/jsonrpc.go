@@ -0,0 +1,150 @@
+// Package jsonrpc implements a minimal JSON-RPC 2.0 dispatcher for the
+// -proto=jsonrpc connection mode, so the server can be driven from
+// standard JSON-RPC clients rather than only raw telnet.
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/WenYanelly16/TCP-Echo-Server-app/codec"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/ratelimit"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/stats"
+)
+
+// rateLimitStrikes is how many consecutive rate-limit violations a
+// connection is allowed before it is disconnected for flooding, mirroring
+// the hub package's line/length connections.
+const rateLimitStrikes = 5
+
+// Version is the JSON-RPC protocol version this package speaks.
+const Version = "2.0"
+
+// Error codes defined by the JSON-RPC 2.0 spec that this server can emit.
+const (
+	CodeParseError     = -32700
+	CodeMethodNotFound = -32601
+)
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// Handler implements one JSON-RPC method.
+type Handler func(params json.RawMessage) (interface{}, *Error)
+
+// Registry maps method names to handlers.
+type Registry struct {
+	methods map[string]Handler
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{methods: make(map[string]Handler)}
+}
+
+// Register adds a handler for method.
+func (r *Registry) Register(method string, h Handler) {
+	r.methods[method] = h
+}
+
+// Dispatch parses raw as a JSON-RPC request and invokes the matching
+// handler. hasResponse is false for notifications (no "id" field), which
+// must never produce a response.
+func (r *Registry) Dispatch(raw string) (resp string, hasResponse bool) {
+	var req request
+	if err := json.Unmarshal([]byte(raw), &req); err != nil {
+		return encode(response{JSONRPC: Version, Error: &Error{Code: CodeParseError, Message: "parse error"}}), true
+	}
+
+	isNotification := len(req.ID) == 0 || string(req.ID) == "null"
+
+	h, ok := r.methods[req.Method]
+	if !ok {
+		if isNotification {
+			return "", false
+		}
+		return encode(response{JSONRPC: Version, Error: &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}, ID: req.ID}), true
+	}
+
+	result, rpcErr := h(req.Params)
+	if isNotification {
+		return "", false
+	}
+	return encode(response{JSONRPC: Version, Result: result, Error: rpcErr, ID: req.ID}), true
+}
+
+func encode(resp response) string {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return `{"jsonrpc":"2.0","error":{"code":-32603,"message":"internal error"},"id":null}`
+	}
+	return string(b)
+}
+
+// Serve reads requests from c until it errors, the connection closes, or
+// conn is disconnected for idling or flooding, dispatching each request
+// through reg and writing back any non-notification response. idleTimeout
+// closes conn after that long without a message (0 disables, matching
+// hub.Options.IdleTimeout); limiter caps inbound messages/sec, disconnecting
+// after rateLimitStrikes consecutive violations (nil disables, matching
+// hub's per-connection limiter).
+func Serve(conn net.Conn, c codec.Codec, reg *Registry, idleTimeout time.Duration, limiter *ratelimit.Bucket) {
+	stats.ConnectionOpened()
+	defer stats.ConnectionClosed()
+
+	var idleTimer *time.Timer
+	if idleTimeout > 0 {
+		idleTimer = time.AfterFunc(idleTimeout, func() { conn.Close() })
+		defer idleTimer.Stop()
+	}
+
+	strikes := 0
+	for {
+		raw, err := c.ReadMessage()
+		if err != nil {
+			if err == codec.ErrFrameTooLarge {
+				c.WriteError(err)
+			}
+			return
+		}
+		if idleTimer != nil {
+			idleTimer.Reset(idleTimeout)
+		}
+		stats.MessageHandled()
+
+		if limiter != nil && !limiter.Allow() {
+			strikes++
+			if strikes >= rateLimitStrikes {
+				return
+			}
+			continue
+		}
+		strikes = 0
+
+		resp, hasResponse := reg.Dispatch(raw)
+		if hasResponse {
+			if err := c.WriteMessage(resp); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func echoRegistry() *Registry {
+	reg := NewRegistry()
+	reg.Register("echo", func(params json.RawMessage) (interface{}, *Error) {
+		var text string
+		if err := json.Unmarshal(params, &text); err != nil {
+			return nil, &Error{Code: CodeParseError, Message: "params must be a JSON string"}
+		}
+		return text, nil
+	})
+	return reg
+}
+
+func TestDispatchMethodNotFound(t *testing.T) {
+	reg := echoRegistry()
+	resp, hasResponse := reg.Dispatch(`{"jsonrpc":"2.0","method":"nope","id":1}`)
+	if !hasResponse {
+		t.Fatalf("expected a response for a request with an id")
+	}
+	if !strings.Contains(resp, "-32601") {
+		t.Fatalf("expected method-not-found code in %q", resp)
+	}
+}
+
+func TestDispatchParseError(t *testing.T) {
+	reg := echoRegistry()
+	resp, hasResponse := reg.Dispatch(`not json`)
+	if !hasResponse {
+		t.Fatalf("expected a response for malformed input")
+	}
+	if !strings.Contains(resp, "-32700") {
+		t.Fatalf("expected parse-error code in %q", resp)
+	}
+}
+
+func TestDispatchNotificationHasNoResponse(t *testing.T) {
+	reg := echoRegistry()
+	_, hasResponse := reg.Dispatch(`{"jsonrpc":"2.0","method":"echo","params":"hi"}`)
+	if hasResponse {
+		t.Fatalf("notifications must not produce a response")
+	}
+}
+
+func TestDispatchSuccess(t *testing.T) {
+	reg := echoRegistry()
+	resp, hasResponse := reg.Dispatch(`{"jsonrpc":"2.0","method":"echo","params":"hi","id":7}`)
+	if !hasResponse {
+		t.Fatalf("expected a response")
+	}
+	if !strings.Contains(resp, `"hi"`) {
+		t.Fatalf("expected echoed params in %q", resp)
+	}
+}
@@ -1,21 +1,51 @@
 package main
 
 import (
-	"bufio"
+	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
 	"time"
+
+	"github.com/WenYanelly16/TCP-Echo-Server-app/clientlog"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/codec"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/hub"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/jsonrpc"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/proxyproto"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/ratelimit"
+	"github.com/WenYanelly16/TCP-Echo-Server-app/stats"
 )
 
 // Global configuration variables
 var (
-	port    int       // Port number to listen on
+	port    int           // Port number to listen on
 	timeout time.Duration // Client inactivity timeout duration
+
+	maxConns      int     // Global concurrent connection cap (0 = unlimited)
+	maxConnsPerIP int     // Per-IP concurrent connection cap (0 = unlimited)
+	msgRate       float64 // Inbound messages/sec allowed per connection (0 = unlimited)
+	msgBurst      int     // Burst capacity for msgRate
+
+	proto        string // Wire protocol: line, length or jsonrpc
+	maxFrameSize int    // Maximum bytes per message, per codec
+
+	logDir        string // Directory for rotated per-client activity logs ("" disables)
+	logFormat     string // Log record format: text or json
+	logMaxSizeMB  int    // Rotate once the current log file exceeds this size
+	logMaxAgeDays int    // Prune log backups older than this many days
+	logMaxBackups int    // Prune log backups beyond this count
+	logSyslog     bool   // Also send activity logs to the local syslog daemon
+
+	tlsCert       string // TLS certificate file; enables TLS when set with -tls-key
+	tlsKey        string // TLS private key file
+	tlsMinVersion string // Minimum accepted TLS version: 1.0, 1.1, 1.2 or 1.3
+	mtlsCA        string // CA bundle; when set, requires and verifies client certificates
+	proxyProtocol bool   // Expect a PROXY protocol v2 header at the start of each connection
 )
 
 // init function runs before main() to set up command line flags
@@ -23,11 +53,30 @@ func init() {
 	// Define command line flags with default values and help text
 	flag.IntVar(&port, "port", 4000, "Port to listen on")
 	flag.DurationVar(&timeout, "timeout", 30*time.Second, "Client inactivity timeout")
-	flag.Parse() // Parse the command line arguments
+	flag.IntVar(&maxConns, "max-conns", 0, "Maximum concurrent connections (0 = unlimited)")
+	flag.IntVar(&maxConnsPerIP, "max-conns-per-ip", 0, "Maximum concurrent connections per client IP (0 = unlimited)")
+	flag.Float64Var(&msgRate, "msg-rate", 0, "Maximum inbound messages per second per connection (0 = unlimited)")
+	flag.IntVar(&msgBurst, "msg-burst", 5, "Burst capacity for -msg-rate")
+	flag.StringVar(&proto, "proto", "line", "Wire protocol: line, length or jsonrpc")
+	flag.IntVar(&maxFrameSize, "max-frame-size", codec.DefaultMaxFrameSize, "Maximum bytes per message")
+	flag.StringVar(&logDir, "log-dir", "", "Directory for rotated per-client activity logs (\"\" disables)")
+	flag.StringVar(&logFormat, "log-format", "text", "Log record format: text or json")
+	flag.IntVar(&logMaxSizeMB, "log-max-size-mb", 100, "Rotate the activity log once it exceeds this size in MB")
+	flag.IntVar(&logMaxAgeDays, "log-max-age-days", 28, "Prune activity log backups older than this many days")
+	flag.IntVar(&logMaxBackups, "log-max-backups", 10, "Prune activity log backups beyond this count")
+	flag.BoolVar(&logSyslog, "log-syslog", false, "Also send activity logs to the local syslog daemon")
+	flag.StringVar(&tlsCert, "tls-cert", "", "TLS certificate file; enables TLS when set with -tls-key")
+	flag.StringVar(&tlsKey, "tls-key", "", "TLS private key file")
+	flag.StringVar(&tlsMinVersion, "tls-min-version", "1.2", "Minimum accepted TLS version: 1.0, 1.1, 1.2 or 1.3")
+	flag.StringVar(&mtlsCA, "mtls-ca", "", "CA bundle; when set, requires and verifies client certificates")
+	flag.BoolVar(&proxyProtocol, "proxy-protocol", false, "Expect a PROXY protocol v2 header at the start of each connection")
 }
 
 // main function - entry point of the server
 func main() {
+	flag.Parse() // Parse the command line arguments; done here, not in init,
+	// so `go test` can register its own flags (-test.*) first.
+
 	// Create a TCP listener on the specified port
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
@@ -37,167 +86,135 @@ func main() {
 
 	log.Printf("Server listening on :%d", port)
 
-	// Main server loop - accepts incoming connections
-	for {
-		conn, err := listener.Accept() // Wait for and accept new connections
-		if err != nil {
-			log.Printf("Error accepting connection: %v", err)
-			continue // Skip errors and keep accepting other connections
-		}
-
-		// Handle each connection in a separate goroutine for concurrency
-		go handleConnection(conn)
+	switch proto {
+	case "", "line", "length", "jsonrpc":
+	default:
+		log.Fatalf("invalid -proto %q: must be line, length or jsonrpc", proto)
 	}
-}
-
-// handleConnection manages an individual client connection
-func handleConnection(conn net.Conn) {
-	clientAddr := conn.RemoteAddr().String() // Get client's address
-	log.Printf("Client connected: %s", clientAddr)
 
-	// Create a unique log file for this client (replace colons in address)
-	logFile, err := os.Create(fmt.Sprintf("%s.log", strings.ReplaceAll(clientAddr, ":", "_")))
+	logger, err := clientlog.New(clientlog.Config{
+		Dir:        logDir,
+		Format:     clientlog.Format(logFormat),
+		MaxSizeMB:  logMaxSizeMB,
+		MaxAgeDays: logMaxAgeDays,
+		MaxBackups: logMaxBackups,
+		Syslog:     logSyslog,
+	})
 	if err != nil {
-		log.Printf("Error creating log file for %s: %v", clientAddr, err)
-		return
+		log.Fatalf("Failed to start activity logger: %v", err)
 	}
-	defer logFile.Close() // Ensure log file is closed when done
-
-	// Deferred function to clean up when connection ends
-	defer func() {
-		conn.Close() // Close the connection
-		log.Printf("Client disconnected: %s", clientAddr)
-	}()
+	defer logger.Close()
 
-	// Create buffered reader/writer for the connection
-	reader := bufio.NewReader(conn)
-	writer := bufio.NewWriter(conn)
+	tlsConfig, err := buildTLSConfig(tlsCert, tlsKey, tlsMinVersion, mtlsCA)
+	if err != nil {
+		log.Fatalf("TLS configuration error: %v", err)
+	}
+	if tlsConfig != nil {
+		log.Printf("TLS enabled (min version %s, client certs required: %v)", tlsMinVersion, mtlsCA != "")
+	}
 
-	// Channel to track client activity for timeout purposes
-	activity := make(chan bool)
-	defer close(activity) // Clean up channel when done
+	h := hub.New(hub.Options{MsgRate: msgRate, MsgBurst: msgBurst, IdleTimeout: timeout, Logger: logger})
+	adm := newAdmission(maxConns, maxConnsPerIP)
+	reg := newJSONRPCRegistry()
 
-	// Goroutine to handle inactivity timeout
+	// Broadcast a shutdown notice and drain clients on SIGINT/SIGTERM instead
+	// of just dying mid-conversation.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		timer := time.NewTimer(timeout)
-		defer timer.Stop()
-		for {
-			select {
-			case <-activity:
-				// Reset timer on client activity
-				if !timer.Stop() {
-					<-timer.C
-				}
-				timer.Reset(timeout)
-			case <-timer.C:
-				// Timeout reached - disconnect client
-				writer.WriteString("Connection timed out due to inactivity\n")
-				writer.Flush()
-				conn.Close()
-				return
-			}
-		}
+		sig := <-sigCh
+		log.Printf("Received %s, shutting down", sig)
+		listener.Close()
+		h.Shutdown("server is shutting down, goodbye")
+		os.Exit(0)
 	}()
 
-	// Main message handling loop
+	// Main server loop - accepts incoming connections
 	for {
-		// Set read deadline to detect half-open connections
-		conn.SetReadDeadline(time.Now().Add(1 * time.Second))
-
-		// Read message until newline
-		message, err := reader.ReadString('\n')
+		conn, err := listener.Accept() // Wait for and accept new connections
 		if err != nil {
-			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				// Handle read timeout (not necessarily inactivity)
-				select {
-				case activity <- true: // Signal activity if needed
-					continue
-				default:
-					continue
-				}
-			}
-			if err == io.EOF {
-				return // Client closed connection normally
+			if ne, ok := err.(*net.OpError); ok && ne.Err.Error() == "use of closed network connection" {
+				return // Listener closed during shutdown
 			}
-			log.Printf("Error reading from %s: %v", clientAddr, err)
-			return // Other errors - close connection
+			log.Printf("Error accepting connection: %v", err)
+			continue // Skip errors and keep accepting other connections
 		}
 
-		// Signal that client is active
-		activity <- true
-
-		// Log the raw message to console and file
-		log.Printf("Message from %s: %s", clientAddr, strings.TrimSpace(message))
-		fmt.Fprintf(logFile, "[%s] %s\n", time.Now().Format(time.RFC3339), strings.TrimSpace(message))
+		if tc, ok := conn.(*net.TCPConn); ok {
+			tc.SetKeepAlive(true)
+			tc.SetKeepAlivePeriod(30 * time.Second)
+		}
 
-		// Clean the message by trimming whitespace
-		message = strings.TrimSpace(message)
+		if proxyProtocol {
+			realAddr, err := proxyproto.ReadHeader(conn)
+			if err != nil {
+				log.Printf("Rejecting %s: bad PROXY protocol header: %v", conn.RemoteAddr(), err)
+				conn.Close()
+				continue
+			}
+			if realAddr != nil {
+				conn = proxyproto.WithRemoteAddr(conn, realAddr)
+			}
+		}
 
-		// Handle empty message case
-		if message == "" {
-			writer.WriteString("Say something...\n")
-			writer.Flush()
-			continue
+		var presetNick string
+		if tlsConfig != nil {
+			tlsConn := tls.Server(conn, tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				log.Printf("TLS handshake failed for %s: %v", conn.RemoteAddr(), err)
+				tlsConn.Close()
+				continue
+			}
+			if mtlsCA != "" {
+				if peers := tlsConn.ConnectionState().PeerCertificates; len(peers) > 0 {
+					presetNick = peers[0].Subject.CommonName
+				}
+			}
+			conn = tlsConn
 		}
 
-		// Check for message length overflow
-		if len(message) > 1024 {
-			writer.WriteString("Error: Message too long (max 1024 bytes)\n")
-			writer.Flush()
+		release, reason, ok := adm.acquire(conn)
+		if !ok {
+			log.Printf("Rejecting %s: %s", conn.RemoteAddr(), reason)
+			fmt.Fprintf(conn, "%s\n", reason)
+			conn.Close()
 			continue
 		}
 
-		// Handle different message types
-		switch {
-		case strings.HasPrefix(message, "/"):
-			// Process commands starting with /
-			handleCommand(message, writer, conn)
-		case strings.EqualFold(message, "hello"):
-			// Special response for "hello"
-			writer.WriteString("Hi there!\n")
-		case strings.EqualFold(message, "bye"):
-			// Special response for "bye" then disconnect
-			writer.WriteString("Goodbye!\n")
-			writer.Flush()
-			return
-		default:
-			// Default behavior - echo the message
-			writer.WriteString(message + "\n")
-		}
+		c, _ := codec.New(proto, conn, maxFrameSize) // proto already validated at startup
 
-		// Flush the writer buffer to ensure message is sent
-		if err := writer.Flush(); err != nil {
-			log.Printf("Error writing to %s: %v", clientAddr, err)
-			return
-		}
+		// Handle each connection in a separate goroutine for concurrency
+		go func() {
+			defer release()
+			if proto == "jsonrpc" {
+				var limiter *ratelimit.Bucket
+				if msgRate > 0 {
+					limiter = ratelimit.New(msgRate, msgBurst)
+				}
+				jsonrpc.Serve(conn, c, reg, timeout, limiter)
+				conn.Close()
+				return
+			}
+			h.Serve(conn, c, presetNick)
+		}()
 	}
 }
 
-// handleCommand processes special commands from the client
-func handleCommand(cmd string, writer *bufio.Writer, conn net.Conn) {
-	// Split command into parts (command and arguments)
-	parts := strings.SplitN(cmd, " ", 2)
-	command := strings.TrimSpace(parts[0])
-
-	// Process different commands
-	switch command {
-	case "/time":
-		// Return current server time
-		writer.WriteString(time.Now().Format(time.RFC3339) + "\n")
-	case "/quit":
-		// Close the connection
-		writer.WriteString("Closing connection\n")
-		writer.Flush()
-		conn.Close()
-	case "/echo":
-		// Echo back the provided message
-		if len(parts) > 1 {
-			writer.WriteString(parts[1] + "\n")
-		} else {
-			writer.WriteString("Usage: /echo <message>\n")
+// newJSONRPCRegistry builds the method table served by -proto=jsonrpc.
+func newJSONRPCRegistry() *jsonrpc.Registry {
+	reg := jsonrpc.NewRegistry()
+	reg.Register("/time", func(params json.RawMessage) (interface{}, *jsonrpc.Error) {
+		return time.Now().Format(time.RFC3339), nil
+	})
+	reg.Register("/echo", func(params json.RawMessage) (interface{}, *jsonrpc.Error) {
+		var text string
+		if err := json.Unmarshal(params, &text); err != nil {
+			return nil, &jsonrpc.Error{Code: jsonrpc.CodeParseError, Message: "params must be a JSON string"}
 		}
-	default:
-		// Unknown command response
-		writer.WriteString("Unknown command\n")
-	}
+		return text, nil
+	})
+	reg.Register("/stats", func(params json.RawMessage) (interface{}, *jsonrpc.Error) {
+		return stats.Get(), nil
+	})
+	return reg
 }
@@ -0,0 +1,109 @@
+// Package proxyproto parses the HAProxy PROXY protocol v2 binary header,
+// so the server reports a client's real address when placed behind a load
+// balancer instead of the balancer's own address.
+package proxyproto
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+var signature = [12]byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// ErrMalformedHeader is returned when the bytes at the start of a
+// connection don't form a valid PROXY protocol v2 header.
+var ErrMalformedHeader = errors.New("proxyproto: malformed PROXY protocol v2 header")
+
+const (
+	cmdLocal = 0x0
+	cmdProxy = 0x1
+
+	famUnspec = 0x0
+	famINET   = 0x1
+	famINET6  = 0x2
+
+	protoStream = 0x1
+)
+
+// ReadHeader consumes a PROXY protocol v2 header from r and returns the
+// original client address it describes. A nil address with a nil error
+// means the header carried no address to report: a healthcheck ("LOCAL"
+// command) or a PROXY command with the UNSPEC address family. Malformed
+// input returns ErrMalformedHeader; a header cut short returns the
+// underlying read error, wrapped.
+func ReadHeader(r io.Reader) (net.Addr, error) {
+	var fixed [16]byte
+	if _, err := io.ReadFull(r, fixed[:]); err != nil {
+		return nil, fmt.Errorf("proxyproto: reading header: %w", err)
+	}
+
+	if [12]byte(fixed[:12]) != signature {
+		return nil, ErrMalformedHeader
+	}
+
+	verCmd := fixed[12]
+	version := verCmd >> 4
+	cmd := verCmd & 0x0f
+	if version != 2 {
+		return nil, ErrMalformedHeader
+	}
+
+	famProto := fixed[13]
+	family := famProto >> 4
+	transport := famProto & 0x0f
+
+	addrLen := binary.BigEndian.Uint16(fixed[14:16])
+	body := make([]byte, addrLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("proxyproto: reading address block: %w", err)
+	}
+
+	if cmd == cmdLocal {
+		return nil, nil
+	}
+	if cmd != cmdProxy {
+		return nil, ErrMalformedHeader
+	}
+	if transport != protoStream {
+		return nil, ErrMalformedHeader
+	}
+
+	switch family {
+	case famINET:
+		if len(body) < 12 {
+			return nil, ErrMalformedHeader
+		}
+		srcIP := net.IP(body[0:4])
+		srcPort := binary.BigEndian.Uint16(body[8:10])
+		return &net.TCPAddr{IP: srcIP, Port: int(srcPort)}, nil
+	case famINET6:
+		if len(body) < 36 {
+			return nil, ErrMalformedHeader
+		}
+		srcIP := net.IP(body[0:16])
+		srcPort := binary.BigEndian.Uint16(body[32:34])
+		return &net.TCPAddr{IP: srcIP, Port: int(srcPort)}, nil
+	case famUnspec:
+		return nil, nil
+	default:
+		return nil, ErrMalformedHeader
+	}
+}
+
+// Conn wraps a net.Conn to report an address parsed from a PROXY protocol
+// header instead of the immediate peer's address (typically a load
+// balancer).
+type Conn struct {
+	net.Conn
+	remote net.Addr
+}
+
+// WithRemoteAddr wraps conn so RemoteAddr reports remote.
+func WithRemoteAddr(conn net.Conn, remote net.Addr) net.Conn {
+	return &Conn{Conn: conn, remote: remote}
+}
+
+func (c *Conn) RemoteAddr() net.Addr { return c.remote }
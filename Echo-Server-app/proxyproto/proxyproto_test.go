@@ -0,0 +1,71 @@
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func buildV2Header(t *testing.T, cmd, family, transport byte, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.Write(signature[:])
+	buf.WriteByte(0x20 | cmd)
+	buf.WriteByte(family<<4 | transport)
+	var lenBuf [2]byte
+	binary.BigEndian.PutUint16(lenBuf[:], uint16(len(body)))
+	buf.Write(lenBuf[:])
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func TestReadHeaderIPv4(t *testing.T) {
+	body := make([]byte, 12)
+	copy(body[0:4], net.ParseIP("203.0.113.7").To4())
+	copy(body[4:8], net.ParseIP("198.51.100.1").To4())
+	binary.BigEndian.PutUint16(body[8:10], 5555)
+	binary.BigEndian.PutUint16(body[10:12], 4000)
+
+	header := buildV2Header(t, cmdProxy, famINET, protoStream, body)
+
+	addr, err := ReadHeader(bytes.NewReader(header))
+	if err != nil {
+		t.Fatalf("ReadHeader: %v", err)
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		t.Fatalf("expected *net.TCPAddr, got %T", addr)
+	}
+	if tcpAddr.IP.String() != "203.0.113.7" || tcpAddr.Port != 5555 {
+		t.Fatalf("got %v, want 203.0.113.7:5555", tcpAddr)
+	}
+}
+
+func TestReadHeaderLocal(t *testing.T) {
+	header := buildV2Header(t, cmdLocal, famUnspec, 0, nil)
+
+	addr, err := ReadHeader(bytes.NewReader(header))
+	if err != nil {
+		t.Fatalf("ReadHeader: %v", err)
+	}
+	if addr != nil {
+		t.Fatalf("expected nil address for LOCAL command, got %v", addr)
+	}
+}
+
+func TestReadHeaderRejectsBadSignature(t *testing.T) {
+	bad := append([]byte("not a proxy header!!"), make([]byte, 16)...)
+	if _, err := ReadHeader(bytes.NewReader(bad)); err != ErrMalformedHeader {
+		t.Fatalf("got err %v, want ErrMalformedHeader", err)
+	}
+}
+
+func TestReadHeaderRejectsWrongVersion(t *testing.T) {
+	header := buildV2Header(t, cmdProxy, famINET, protoStream, make([]byte, 12))
+	header[12] = 0x10 | cmdProxy // version 1, not supported
+
+	if _, err := ReadHeader(bytes.NewReader(header)); err != ErrMalformedHeader {
+		t.Fatalf("got err %v, want ErrMalformedHeader", err)
+	}
+}
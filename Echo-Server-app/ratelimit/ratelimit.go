@@ -0,0 +1,50 @@
+// Package ratelimit provides a small token-bucket rate limiter shared by
+// every connection-serving package (hub, jsonrpc) that needs to cap inbound
+// messages per second.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a simple per-connection rate limiter. It refills at rate
+// tokens/sec up to burst capacity.
+type Bucket struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+}
+
+// New creates a Bucket starting at full burst capacity.
+func New(rate float64, burst int) *Bucket {
+	return &Bucket{
+		rate:     rate,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		lastFill: time.Now(),
+	}
+}
+
+// Allow reports whether a message may be processed now, consuming one
+// token if so.
+func (b *Bucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(b.lastFill).Seconds()
+	b.lastFill = now
+	b.tokens += elapsed * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
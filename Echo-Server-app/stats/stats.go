@@ -0,0 +1,43 @@
+// Package stats holds process-wide connection and message counters,
+// exposed to clients via the JSON-RPC "/stats" method.
+package stats
+
+import "sync/atomic"
+
+// Snapshot is a point-in-time copy of the server's counters.
+type Snapshot struct {
+	TotalConnections  int64 `json:"total_connections"`
+	ActiveConnections int64 `json:"active_connections"`
+	MessagesHandled   int64 `json:"messages_handled"`
+}
+
+var (
+	totalConnections  int64
+	activeConnections int64
+	messagesHandled   int64
+)
+
+// ConnectionOpened records a newly accepted connection.
+func ConnectionOpened() {
+	atomic.AddInt64(&totalConnections, 1)
+	atomic.AddInt64(&activeConnections, 1)
+}
+
+// ConnectionClosed records a connection ending.
+func ConnectionClosed() {
+	atomic.AddInt64(&activeConnections, -1)
+}
+
+// MessageHandled records one inbound message having been processed.
+func MessageHandled() {
+	atomic.AddInt64(&messagesHandled, 1)
+}
+
+// Get returns the current counter values.
+func Get() Snapshot {
+	return Snapshot{
+		TotalConnections:  atomic.LoadInt64(&totalConnections),
+		ActiveConnections: atomic.LoadInt64(&activeConnections),
+		MessagesHandled:   atomic.LoadInt64(&messagesHandled),
+	}
+}
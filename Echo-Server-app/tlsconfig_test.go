@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert generates a throwaway self-signed certificate/key
+// pair for exercising buildTLSConfig without a real CA.
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	certOut, err := os.Create(certFile)
+	if err != nil {
+		t.Fatalf("creating cert file: %v", err)
+	}
+	pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	certOut.Close()
+
+	keyOut, err := os.Create(keyFile)
+	if err != nil {
+		t.Fatalf("creating key file: %v", err)
+	}
+	pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	keyOut.Close()
+
+	return certFile, keyFile
+}
+
+func TestBuildTLSConfigDisabledByDefault(t *testing.T) {
+	cfg, err := buildTLSConfig("", "", "1.2", "")
+	if err != nil {
+		t.Fatalf("buildTLSConfig: %v", err)
+	}
+	if cfg != nil {
+		t.Fatalf("expected nil config when no cert/key given")
+	}
+}
+
+func TestBuildTLSConfigLoadsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeSelfSignedCert(t, dir)
+
+	cfg, err := buildTLSConfig(certFile, keyFile, "1.2", "")
+	if err != nil {
+		t.Fatalf("buildTLSConfig: %v", err)
+	}
+	if cfg == nil {
+		t.Fatalf("expected a non-nil config")
+	}
+	if cfg.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("got MinVersion %d, want TLS 1.2", cfg.MinVersion)
+	}
+	if cfg.ClientAuth != tls.NoClientCert {
+		t.Fatalf("expected no client cert requirement without -mtls-ca")
+	}
+}
+
+func TestBuildTLSConfigRejectsHalfConfiguredPair(t *testing.T) {
+	if _, err := buildTLSConfig("cert.pem", "", "1.2", ""); err == nil {
+		t.Fatalf("expected an error when only -tls-cert is set")
+	}
+}
+
+func TestBuildTLSConfigRejectsInvalidMinVersion(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeSelfSignedCert(t, dir)
+
+	if _, err := buildTLSConfig(certFile, keyFile, "9.9", ""); err == nil {
+		t.Fatalf("expected an error for an invalid -tls-min-version")
+	}
+}